@@ -3,12 +3,12 @@ package main
 import (
 	"bufio"
 	"bytes"
+	"flag"
 	"fmt"
 	"log/slog"
 	"os"
 	"os/exec"
 	"path/filepath"
-	"regexp"
 	"strings"
 	"sync"
 )
@@ -16,13 +16,65 @@ import (
 const (
 	success         = "SUCCESS"
 	fail            = "FAIL"
-	searchString    = "Changeset changelog/ddl.xml"
 	dbTypeMysql     = "mysql"
 	dbTypeSqlServer = "sqlserver"
 	dbTypeOracle    = "oracle"
+	dbTypePostgres  = "postgres"
+	dbTypeSqlite    = "sqlite"
+	// diffPropertiesFile 是 diffChangeLog 用的源/目标连接配置，不是一个目标方言
+	diffPropertiesFile = "diff"
 )
 
+// targetDialects 是支持的目标数据库类型及其转换函数的注册表
+// 新增数据库类型时，在这里注册对应的转换函数，并在 config/ 下放一份 <dbType>.properties 即可参与生成
+// 转换函数的 propertiesFile 参数用于去除表名，默认流程和 serve 模式分别传入各自的 properties 路径
+var targetDialects = map[string]func(propertiesFile, sql string) string{
+	dbTypeMysql:     convertMysql,
+	dbTypeSqlServer: convertSqlServer,
+	dbTypeOracle:    convertOracle,
+	dbTypePostgres:  convertPostgres,
+	dbTypeSqlite:    convertSqlite,
+}
+
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "serve":
+			// serve 子命令：以 HTTP 服务的形式按需提供 diff 和 sql 生成能力
+			runServe()
+			return
+		case "from-binlog":
+			// from-binlog 子命令：从 MySQL binlog 重建 DDL/DML，接入既有的转换+写出流水线
+			runFromBinlog()
+			return
+		}
+	}
+
+	runGenerate()
+}
+
+// withRollback 控制是否在生成正向 sql 的同时生成 rollback sql，默认开启
+// rollbackCount 限制 rollback 涉及的 changeset 数量，0 表示不限制（对应 futureRollbackSQL）
+// emitModelsDir 非空时，在 changelog 生成后额外解析出 Go struct 模型写入该目录
+// gormTags 控制生成模型时是否同时带上 gorm tag
+var (
+	withRollback  bool
+	rollbackCount int
+	emitModelsDir string
+	gormTags      bool
+)
+
+func init() {
+	flag.BoolVar(&withRollback, "with-rollback", true, "同时生成 rollback sql")
+	flag.IntVar(&rollbackCount, "rollback-count", 0, "限制生成 rollback 的 changeset 数量，0 表示不限制")
+	flag.StringVar(&emitModelsDir, "emit-models", "", "解析 changelog 生成 Go struct 模型的输出目录，留空表示不生成")
+	flag.BoolVar(&gormTags, "gorm-tags", false, "生成模型时同时带上 gorm tag")
+}
+
+// runGenerate 是默认的一次性生成流程：diffChangeLog -> 按目标方言并发 updateSql（以及可选的 rollback sql）
+func runGenerate() {
+	flag.Parse()
+
 	// 设置 JAVA_TOOL_OPTIONS 环境变量
 	err := os.Setenv("JAVA_TOOL_OPTIONS", "-Dfile.encoding=UTF-8")
 	if err != nil {
@@ -44,14 +96,20 @@ func main() {
 	ch := make(chan string)
 
 	// 生成 changelog-ddl.xml 文件
-	go generateChangeLog(liquibaseDir, ch)
+	go generateChangeLog(liquibaseDir, "changelog/ddl.xml", "config/diff.properties", ch)
 
 	// 生成 sql
 	select {
 	case s := <-ch:
 		if success == s {
 			// 生成 changelog-ddl.xml 文件成功
-			generateSql(liquibaseDir)
+			generateSql(liquibaseDir, "changelog/ddl.xml", "./out")
+
+			if emitModelsDir != "" {
+				if err := emitModelsFromChangeLog("changelog/ddl.xml", emitModelsDir, gormTags); err != nil {
+					slog.Error("Error emitting models:", "msg", err)
+				}
+			}
 		} else {
 			// 生成 changelog-ddl.xml 文件失败，不做处理
 		}
@@ -64,11 +122,11 @@ func main() {
 	}
 }
 
-func generateChangeLog(liquibaseDir string, ch chan string) {
-	slog.Info("Start to generate changelog/ddl.xml")
+// generateChangeLog 执行 diffChangeLog，changeLogFile/propertiesFile 可指向临时路径，供 serve 模式复用
+func generateChangeLog(liquibaseDir, changeLogFile, propertiesFile string, ch chan string) {
+	slog.Info("Start to generate changelog", "file", changeLogFile)
 
-	// 生成 changelog-ddl.xml 文件
-	cmd := exec.Command(liquibaseDir+"/liquibase", "--changeLogFile=changelog/ddl.xml", "--defaultsFile=config/diff.properties", "diffChangeLog")
+	cmd := exec.Command(liquibaseDir+"/liquibase", fmt.Sprintf("--changeLogFile=%s", changeLogFile), fmt.Sprintf("--defaultsFile=%s", propertiesFile), "diffChangeLog")
 	//cmd.Stdout = os.Stdout
 	//cmd.Stderr = os.Stderr
 
@@ -80,60 +138,145 @@ func generateChangeLog(liquibaseDir string, ch chan string) {
 		return
 	}
 
-	slog.Info("Generate changelog/ddl.xml successfully")
+	slog.Info("Generate changelog successfully", "file", changeLogFile)
 
 	// 执行完毕向 channel 里发送通知
 	ch <- success
 }
 
-func generateSql(liquibaseDir string) {
-	var wg sync.WaitGroup
-	wg.Add(3)
-
-	// mysql
-	go func() {
-		defer wg.Done()
-		doGenerateSql(liquibaseDir, dbTypeMysql)
-	}()
-
-	// sqlserver
-	go func() {
-		defer wg.Done()
-		doGenerateSql(liquibaseDir, dbTypeSqlServer)
-	}()
-
-	// oracle
-	go func() {
-		defer wg.Done()
-		doGenerateSql(liquibaseDir, dbTypeOracle)
-	}()
+// generateSql 对 changeLogFile 按已发现的目标方言并发生成 sql（以及可选的 rollback sql），写入 outDir
+func generateSql(liquibaseDir, changeLogFile, outDir string) {
+	dbTypes, err := discoverDbTypes()
+	if err != nil {
+		slog.Error("Error discovering target db types:", "msg", err)
+		return
+	}
 
+	forwardLines := make([][]string, len(dbTypes))
+	rollbackLines := make([][]string, len(dbTypes))
+
+	var wg sync.WaitGroup
+	for i, dbType := range dbTypes {
+		wg.Add(1)
+		go func(i int, dbType string) {
+			defer wg.Done()
+			forwardLines[i] = doGenerateSql(liquibaseDir, changeLogFile, outDir, dbType)
+		}(i, dbType)
+
+		if withRollback {
+			wg.Add(1)
+			go func(i int, dbType string) {
+				defer wg.Done()
+				rollbackLines[i] = doRollbackSql(liquibaseDir, changeLogFile, outDir, dbType)
+			}(i, dbType)
+		}
+	}
 	wg.Wait()
-}
 
-func doGenerateSql(liquibaseDir string, dbType string) {
-	slog.Info("Start to generate sql", "db", dbType)
-	// 生成 changelog-ddl.xml 文件
-	cmd := exec.Command(liquibaseDir+"/liquibase", "--changeLogFile=changelog/ddl.xml", fmt.Sprintf("--defaultsFile=config/%s.properties", dbType), "updateSql")
+	if !withRollback {
+		return
+	}
 
-	out, err := cmd.CombinedOutput()
+	for i, dbType := range dbTypes {
+		if err := writeManifest(dbType, changeLogFile, outDir, forwardLines[i], rollbackLines[i]); err != nil {
+			slog.Error("Error writing manifest:", "msg", err, "db", dbType)
+		}
+	}
+}
+
+// discoverDbTypes 从 config/*.properties 的文件名中找出已配置且已注册转换函数的目标数据库类型
+func discoverDbTypes() ([]string, error) {
+	matches, err := filepath.Glob("config/*.properties")
 	if err != nil {
-		slog.Error("Error executing Liquibase command updateSql:", "msg", out)
-		return
+		return nil, err
 	}
 
-	// 从 out 中解析出想要的 sql
-	lines := extractNextLines(out, searchString, dbType)
+	var dbTypes []string
+	for _, m := range matches {
+		dbType := strings.TrimSuffix(filepath.Base(m), ".properties")
+		if dbType == diffPropertiesFile {
+			continue
+		}
+		if _, ok := targetDialects[dbType]; !ok {
+			slog.Warn("Unknown db type in config, skipping", "dbType", dbType)
+			continue
+		}
+		dbTypes = append(dbTypes, dbType)
+	}
+	return dbTypes, nil
+}
+
+func doGenerateSql(liquibaseDir, changeLogFile, outDir, dbType string) []string {
+	slog.Info("Start to generate sql", "db", dbType, "changeLogFile", changeLogFile)
+
+	propertiesFile := fmt.Sprintf("config/%s.properties", dbType)
+	lines, err := runUpdateSql(liquibaseDir, changeLogFile, propertiesFile, dbType)
+	if err != nil {
+		slog.Error("Error executing Liquibase command updateSql:", "msg", err)
+		return nil
+	}
 
 	// 将 sql 写入文件
-	err = writeToFile(lines, fmt.Sprintf("./out/%s.sql", dbType))
+	err = writeToFile(lines, fmt.Sprintf("%s/%s.sql", outDir, dbType))
 	if err != nil {
 		slog.Error("Error writing to file:", "msg", err)
 	}
 	slog.Info("Generate sql successfully", "db", dbType)
+	return lines
+}
+
+// runUpdateSql 对 changeLogFile 执行 updateSql，并按 dbType 解析、转换出目标方言的 sql
+// changeLogFile/propertiesFile 可指向临时路径，供 serve 模式复用
+func runUpdateSql(liquibaseDir, changeLogFile, propertiesFile, dbType string) ([]string, error) {
+	cmd := exec.Command(liquibaseDir+"/liquibase", fmt.Sprintf("--changeLogFile=%s", changeLogFile), fmt.Sprintf("--defaultsFile=%s", propertiesFile), "updateSql")
+
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("error executing liquibase updateSql: %w: %s", err, out)
+	}
+
+	return extractNextLines(out, changeLogFile, propertiesFile, dbType), nil
+}
+
+// doRollbackSql 生成 dbType 方言的 rollback sql，写入 outDir/<db>.rollback.sql
+// rollbackCount > 0 时只回滚最近的 N 个 changeset（rollbackCountSQL），否则回滚全部（futureRollbackSQL）
+func doRollbackSql(liquibaseDir, changeLogFile, outDir, dbType string) []string {
+	slog.Info("Start to generate rollback sql", "db", dbType, "changeLogFile", changeLogFile)
+
+	propertiesFile := fmt.Sprintf("config/%s.properties", dbType)
+	command := "futureRollbackSQL"
+	args := []string{
+		fmt.Sprintf("--changeLogFile=%s", changeLogFile),
+		fmt.Sprintf("--defaultsFile=%s", propertiesFile),
+	}
+	if rollbackCount > 0 {
+		command = "rollbackCountSQL"
+		args = append(args, command, fmt.Sprintf("%d", rollbackCount))
+	} else {
+		args = append(args, command)
+	}
+
+	cmd := exec.Command(liquibaseDir+"/liquibase", args...)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		slog.Error("Error executing Liquibase rollback command:", "msg", err, "db", dbType)
+		return nil
+	}
+
+	lines := extractNextLines(out, changeLogFile, propertiesFile, dbType)
+
+	err = writeToFile(lines, fmt.Sprintf("%s/%s.rollback.sql", outDir, dbType))
+	if err != nil {
+		slog.Error("Error writing rollback file:", "msg", err)
+	}
+	slog.Info("Generate rollback sql successfully", "db", dbType)
+	return lines
 }
 
-func extractNextLines(data []byte, searchString string, dbType string) []string {
+// extractNextLines 从 updateSql/rollbackSql 的输出里提取出 changeLogFile 对应的 changeset sql
+// propertiesFile 会被转换函数用来去除表名，默认流程和 serve 模式分别传入各自的 properties 路径
+func extractNextLines(data []byte, changeLogFile, propertiesFile, dbType string) []string {
+	searchString := fmt.Sprintf("Changeset %s", changeLogFile)
 	var result []string
 
 	// 提取文件内容的标志
@@ -147,16 +290,10 @@ func extractNextLines(data []byte, searchString string, dbType string) []string
 			if strings.Contains(line, "INSERT INTO") {
 				flag = false
 			} else {
-				// 类型映射
-				var sql string
-				// 类型转换
-				switch dbType {
-				case dbTypeMysql:
-					sql = convertMysql(line)
-				case dbTypeSqlServer:
-					sql = convertSqlServer(line)
-				case dbTypeOracle:
-					sql = convertOracle(line)
+				// 类型转换，按 dbType 从注册表里找对应的转换函数
+				sql := line
+				if convert, ok := targetDialects[dbType]; ok {
+					sql = convert(propertiesFile, line)
 				}
 				// 开始提取内容
 				result = append(result, sql)
@@ -172,82 +309,49 @@ func extractNextLines(data []byte, searchString string, dbType string) []string
 }
 
 // convertMysql 转换 mysql 的数据类型
-// 自动生成的数据类型有时候不满足现状
-func convertMysql(sql string) string {
-	// 去除表名
-	sql = removeTableName(sql, dbTypeMysql)
-	return sql
+// 具体规则（包括去除表名）从 config/mappings.yaml 加载，便于不重新编译就能调整
+func convertMysql(propertiesFile, sql string) string {
+	return applyMapping(dbTypeMysql, propertiesFile, sql)
 }
 
 // convertOracle 转换 oracle 的数据类型
-// 自动生成的数据类型有时候不满足现状
-func convertOracle(sql string) string {
-	// VARCHAR2(xx) -> VARCHAR2(xx char)
-	varchar2Reg := regexp.MustCompile(`VARCHAR2\([0-9]+\)`)
-	varchar2Arr := varchar2Reg.FindStringSubmatch(sql)
-	for _, varchar2 := range varchar2Arr {
-		newStr := varchar2[0:len(varchar2)-1] + " char)"
-		sql = strings.ReplaceAll(sql, varchar2, newStr)
-	}
-	// DECIMAL -> NUMBER
-	sql = strings.ReplaceAll(sql, "DECIMAL", "NUMBER")
-
-	// 去除表名
-	sql = removeTableName(sql, dbTypeOracle)
-
-	return sql
+// 具体规则（包括去除表名）从 config/mappings.yaml 加载，便于不重新编译就能调整
+func convertOracle(propertiesFile, sql string) string {
+	return applyMapping(dbTypeOracle, propertiesFile, sql)
 }
 
 // convertSqlServer 转换 sqlServer 的数据类型
-// 自动生成的数据类型有时候不满足现状
-func convertSqlServer(sql string) string {
-	// varchar -> nvarchar
-	sql = strings.ReplaceAll(sql, "varchar", "nvarchar")
-	sql = strings.ReplaceAll(sql, "nnvarchar", "nvarchar")
-	// varchar (max) -> ntext
-	sql = strings.ReplaceAll(sql, "varchar (max)", "ntext")
-	sql = strings.ReplaceAll(sql, "varchar(MAX)", "ntext")
-	sql = strings.ReplaceAll(sql, "nntext", "ntext")
-	// datetime -> datetime2
-	sql = strings.ReplaceAll(sql, "datetime", "datetime2")
-
-	return sql
+// 具体规则从 config/mappings.yaml 加载，便于不重新编译就能调整
+func convertSqlServer(propertiesFile, sql string) string {
+	return applyMapping(dbTypeSqlServer, propertiesFile, sql)
 }
 
-// removeTableName 去除表名
-func removeTableName(sql string, dbType string) string {
-	f, err := os.Open(fmt.Sprintf("config/%s.properties", dbType))
-	if err != nil {
-		slog.Error("Error opening file:", "msg", err)
-	}
-	defer f.Close()
+// convertPostgres 转换 postgres 的数据类型
+// 具体规则（包括去除表名）从 config/mappings.yaml 加载，便于不重新编译就能调整
+func convertPostgres(propertiesFile, sql string) string {
+	return applyMapping(dbTypePostgres, propertiesFile, sql)
+}
 
-	// 逐行读取文件内容，提取需要的
-	scanner := bufio.NewScanner(f)
-	for scanner.Scan() {
-		line := scanner.Text()
-		if strings.Contains(line, "db") {
-			arr := strings.Split(line, ": ")
-			if len(arr) == 2 {
-				sql = strings.ReplaceAll(sql, arr[1]+".", "")
-			}
-		}
-	}
-	return sql
+// convertSqlite 转换 sqlite 的数据类型
+// 具体规则（包括去除表名）从 config/mappings.yaml 加载，便于不重新编译就能调整
+func convertSqlite(propertiesFile, sql string) string {
+	return applyMapping(dbTypeSqlite, propertiesFile, sql)
 }
 
 func writeToFile(lines []string, filename string) error {
 	// 将字符串数组连接成一个长字符串
 	content := strings.Join(lines, "\n")
+	return writeFileBytes([]byte(content), filename)
+}
 
-	// 创建目录
+// writeFileBytes 创建必要的目录后，将 data 写入 filename
+func writeFileBytes(data []byte, filename string) error {
 	err := os.MkdirAll(filepath.Dir(filename), 0755)
 	if err != nil {
 		return fmt.Errorf("error creating directories: %w", err)
 	}
 
-	// 将内容写入文件
-	err = os.WriteFile(filename, []byte(content), 0644)
+	err = os.WriteFile(filename, data, 0644)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// changesetManifestEntry 是 manifest 里单个 changeset 的记录
+type changesetManifestEntry struct {
+	Id           string `json:"id"`
+	SourceFile   string `json:"sourceFile"`
+	ForwardHash  string `json:"forwardHash"`
+	RollbackHash string `json:"rollbackHash,omitempty"`
+}
+
+// writeManifest 将 forwardLines/rollbackLines 按 changeset 分组求摘要，写入 outDir/<db>.manifest.json
+func writeManifest(dbType, changeLogFile, outDir string, forwardLines []string, rollbackLines []string) error {
+	ids, forwardHashes := groupChangesetHashes(changeLogFile, forwardLines)
+	_, rollbackHashes := groupChangesetHashes(changeLogFile, rollbackLines)
+
+	entries := make([]changesetManifestEntry, 0, len(ids))
+	for _, id := range ids {
+		entries = append(entries, changesetManifestEntry{
+			Id:           id,
+			SourceFile:   changeLogFile,
+			ForwardHash:  forwardHashes[id],
+			RollbackHash: rollbackHashes[id],
+		})
+	}
+
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error marshalling manifest: %w", err)
+	}
+
+	return writeFileBytes(data, fmt.Sprintf("%s/%s.manifest.json", outDir, dbType))
+}
+
+// groupChangesetHashes 按 changeset id 对 lines 分组，返回 id 的出现顺序，以及每个 changeset 内容的 sha256 摘要
+func groupChangesetHashes(changeLogFile string, lines []string) (ids []string, hashes map[string]string) {
+	hashes = map[string]string{}
+
+	headerRegex := regexp.MustCompile(fmt.Sprintf(`Changeset %s::([^:]+)::`, regexp.QuoteMeta(changeLogFile)))
+
+	var currentId string
+	var body strings.Builder
+
+	flush := func() {
+		if currentId == "" {
+			return
+		}
+		sum := sha256.Sum256([]byte(body.String()))
+		hashes[currentId] = hex.EncodeToString(sum[:])
+	}
+
+	for _, line := range lines {
+		if m := headerRegex.FindStringSubmatch(line); m != nil {
+			flush()
+			currentId = m[1]
+			ids = append(ids, currentId)
+			body.Reset()
+			continue
+		}
+		body.WriteString(line)
+		body.WriteString("\n")
+	}
+	flush()
+
+	return ids, hashes
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bufio"
+	"log/slog"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v2"
+)
+
+const mappingsFile = "config/mappings.yaml"
+
+// mappingRule 描述一条类型转换规则
+// Match 既可以是字面量，也可以是正则表达式（此时 Regex 为 true，Replace 中可以用 $1 引用分组）
+// When 非空时，只有 sql 中包含这个子串才会应用这条规则，用来限定规则的适用上下文
+type mappingRule struct {
+	Match   string `yaml:"match"`
+	Replace string `yaml:"replace"`
+	Regex   bool   `yaml:"regex"`
+	When    string `yaml:"when"`
+
+	// re 是 Regex 规则预编译好的正则，在 loadMappings 里一次性编译，避免每行 sql 都重新编译
+	re *regexp.Regexp
+}
+
+// dbMapping 是某个 dbType 下的有序规则列表，规则按声明顺序依次应用
+type dbMapping struct {
+	Rules []mappingRule `yaml:"rules"`
+}
+
+// mappingConfig 对应 config/mappings.yaml 的整体结构，key 为 dbType
+type mappingConfig map[string]dbMapping
+
+var (
+	mappingsOnce sync.Once
+	mappings     mappingConfig
+)
+
+// loadMappings 读取并缓存 config/mappings.yaml，进程内只加载一次，正则规则在这里一次性编译好
+// "去除表名"不在这里缓存，因为它依赖调用方传入的 propertiesFile（serve 模式下每个请求各不相同），
+// 由 applyMapping 在每次调用时单独加载，见 tableNameRule
+func loadMappings() mappingConfig {
+	mappingsOnce.Do(func() {
+		mappings = mappingConfig{}
+
+		data, err := os.ReadFile(mappingsFile)
+		if err != nil {
+			slog.Error("Error reading mappings file:", "msg", err)
+			return
+		}
+
+		var cfg mappingConfig
+		if err := yaml.Unmarshal(data, &cfg); err != nil {
+			slog.Error("Error parsing mappings file:", "msg", err)
+			return
+		}
+
+		for _, dbm := range cfg {
+			compileRules(dbm.Rules)
+		}
+		mappings = cfg
+	})
+	return mappings
+}
+
+// compileRules 把每条 regex 规则的正则表达式一次性编译好，填到 rule.re 上
+func compileRules(rules []mappingRule) {
+	for i := range rules {
+		if !rules[i].Regex {
+			continue
+		}
+		re, err := regexp.Compile(rules[i].Match)
+		if err != nil {
+			slog.Error("Error compiling mapping regex:", "msg", err, "pattern", rules[i].Match)
+			continue
+		}
+		rules[i].re = re
+	}
+}
+
+// tableNameRule 从 propertiesFile 里的 "db: xxx" 行派生出一条去除 "xxx." 前缀的内置规则，没有则返回 nil
+func tableNameRule(propertiesFile string) *mappingRule {
+	f, err := os.Open(propertiesFile)
+	if err != nil {
+		slog.Error("Error opening file:", "msg", err)
+		return nil
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.Contains(line, "db") {
+			arr := strings.Split(line, ": ")
+			if len(arr) == 2 && arr[1] != "" {
+				return &mappingRule{Match: arr[1] + "."}
+			}
+		}
+	}
+	return nil
+}
+
+// applyMapping 按 config/mappings.yaml 中 dbType 对应的规则顺序，依次对 sql 做替换，
+// 最后再用 propertiesFile 里声明的 db 去掉表名前缀。When 非空的规则只在 sql 包含该子串时才会应用
+func applyMapping(dbType string, propertiesFile string, sql string) string {
+	rules := loadMappings()[dbType].Rules
+	if rule := tableNameRule(propertiesFile); rule != nil {
+		rules = append(append([]mappingRule{}, rules...), *rule)
+	}
+
+	for _, rule := range rules {
+		if rule.When != "" && !strings.Contains(sql, rule.When) {
+			continue
+		}
+		if rule.Regex {
+			if rule.re == nil {
+				continue
+			}
+			sql = rule.re.ReplaceAllString(sql, rule.Replace)
+		} else {
+			sql = strings.ReplaceAll(sql, rule.Match, rule.Replace)
+		}
+	}
+	return sql
+}
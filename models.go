@@ -0,0 +1,258 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"go/format"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ddlChangeLog 只声明了解析 createTable/addColumn 需要的字段，其余元素原样忽略
+type ddlChangeLog struct {
+	XMLName    xml.Name       `xml:"databaseChangeLog"`
+	ChangeSets []ddlChangeSet `xml:"changeSet"`
+}
+
+type ddlChangeSet struct {
+	CreateTable *ddlCreateTable `xml:"createTable"`
+	AddColumn   *ddlAddColumn   `xml:"addColumn"`
+}
+
+type ddlCreateTable struct {
+	TableName string      `xml:"tableName,attr"`
+	Columns   []ddlColumn `xml:"column"`
+}
+
+type ddlAddColumn struct {
+	TableName string      `xml:"tableName,attr"`
+	Columns   []ddlColumn `xml:"column"`
+}
+
+type ddlColumn struct {
+	Name        string          `xml:"name,attr"`
+	Type        string          `xml:"type,attr"`
+	Constraints *ddlConstraints `xml:"constraints"`
+}
+
+type ddlConstraints struct {
+	Nullable *bool `xml:"nullable,attr"`
+}
+
+// tableSchema 是从 changelog 里聚合出的一张表的列定义，按 createTable/addColumn 出现顺序累积
+type tableSchema struct {
+	Name    string
+	Columns []columnSchema
+}
+
+type columnSchema struct {
+	Name     string
+	Type     string
+	Nullable bool
+}
+
+// emitModelsFromChangeLog 解析 changeLogFile 里的 createTable/addColumn，生成对应的 Go struct 模型到 outDir
+func emitModelsFromChangeLog(changeLogFile, outDir string, withGorm bool) error {
+	tables, err := parseChangeLogTables(changeLogFile)
+	if err != nil {
+		return err
+	}
+	return emitModels(tables, outDir, withGorm)
+}
+
+// parseChangeLogTables 按 changeSet 出现顺序，把 createTable/addColumn 聚合成每张表完整的列定义
+func parseChangeLogTables(changeLogFile string) ([]tableSchema, error) {
+	data, err := os.ReadFile(changeLogFile)
+	if err != nil {
+		return nil, err
+	}
+
+	var log ddlChangeLog
+	if err := xml.Unmarshal(data, &log); err != nil {
+		return nil, fmt.Errorf("error parsing changelog: %w", err)
+	}
+
+	var order []string
+	tables := map[string]*tableSchema{}
+
+	appendColumns := func(tableName string, cols []ddlColumn) {
+		if tableName == "" {
+			return
+		}
+		t, ok := tables[tableName]
+		if !ok {
+			t = &tableSchema{Name: tableName}
+			tables[tableName] = t
+			order = append(order, tableName)
+		}
+		for _, c := range cols {
+			nullable := true
+			if c.Constraints != nil && c.Constraints.Nullable != nil {
+				nullable = *c.Constraints.Nullable
+			}
+			t.Columns = append(t.Columns, columnSchema{Name: c.Name, Type: c.Type, Nullable: nullable})
+		}
+	}
+
+	for _, cs := range log.ChangeSets {
+		if cs.CreateTable != nil {
+			appendColumns(cs.CreateTable.TableName, cs.CreateTable.Columns)
+		}
+		if cs.AddColumn != nil {
+			appendColumns(cs.AddColumn.TableName, cs.AddColumn.Columns)
+		}
+	}
+
+	result := make([]tableSchema, 0, len(order))
+	for _, name := range order {
+		result = append(result, *tables[name])
+	}
+	return result, nil
+}
+
+// emitModels 为每张表写一个 Go struct 文件，内容不变时跳过写入，保证重复执行是幂等的
+func emitModels(tables []tableSchema, outDir string, withGorm bool) error {
+	for _, t := range tables {
+		content, err := renderModel(t, withGorm)
+		if err != nil {
+			return fmt.Errorf("error rendering model for %s: %w", t.Name, err)
+		}
+
+		filename := filepath.Join(outDir, t.Name+".go")
+		if fileUnchanged(filename, content) {
+			continue
+		}
+		if err := writeFileBytes(content, filename); err != nil {
+			return fmt.Errorf("error writing model for %s: %w", t.Name, err)
+		}
+	}
+	return nil
+}
+
+func fileUnchanged(filename string, content []byte) bool {
+	existing, err := os.ReadFile(filename)
+	if err != nil {
+		return false
+	}
+	return bytes.Equal(existing, content)
+}
+
+// renderModel 把一张表渲染成一个 db:"..."（可选 gorm:"..."）的 Go struct
+func renderModel(t tableSchema, withGorm bool) ([]byte, error) {
+	type field struct {
+		name   string
+		goType string
+		dbTag  string
+	}
+
+	imports := map[string]bool{}
+	fields := make([]field, 0, len(t.Columns))
+	for _, c := range t.Columns {
+		goType, imp := columnGoType(c.Type, c.Nullable)
+		if imp != "" {
+			imports[imp] = true
+		}
+		fields = append(fields, field{
+			name:   toPascalCase(c.Name),
+			goType: goType,
+			dbTag:  c.Name,
+		})
+	}
+
+	var sb strings.Builder
+	sb.WriteString("package models\n\n")
+
+	if len(imports) > 0 {
+		sortedImports := make([]string, 0, len(imports))
+		for imp := range imports {
+			sortedImports = append(sortedImports, imp)
+		}
+		sort.Strings(sortedImports)
+
+		sb.WriteString("import (\n")
+		for _, imp := range sortedImports {
+			sb.WriteString(fmt.Sprintf("\t%q\n", imp))
+		}
+		sb.WriteString(")\n\n")
+	}
+
+	structName := toPascalCase(t.Name)
+	sb.WriteString(fmt.Sprintf("// %s 对应表 %s，由 liquix --emit-models 根据 changelog 生成，请勿手动修改\n", structName, t.Name))
+	sb.WriteString(fmt.Sprintf("type %s struct {\n", structName))
+	for _, f := range fields {
+		tag := fmt.Sprintf(`db:"%s"`, f.dbTag)
+		if withGorm {
+			tag += fmt.Sprintf(` gorm:"column:%s"`, f.dbTag)
+		}
+		sb.WriteString(fmt.Sprintf("\t%s %s `%s`\n", f.name, f.goType, tag))
+	}
+	sb.WriteString("}\n")
+
+	return format.Source([]byte(sb.String()))
+}
+
+// columnGoType 把 liquibase 的逻辑列类型映射成 Go 类型，可为空的列优先映射到 sql.Null* / 指针类型
+func columnGoType(dbType string, nullable bool) (goType string, requiredImport string) {
+	base := strings.ToLower(dbType)
+
+	switch {
+	case strings.HasPrefix(base, "bigint"):
+		if nullable {
+			return "sql.NullInt64", "database/sql"
+		}
+		return "int64", ""
+	case strings.HasPrefix(base, "int"), strings.HasPrefix(base, "smallint"), strings.HasPrefix(base, "mediumint"):
+		if nullable {
+			return "sql.NullInt64", "database/sql"
+		}
+		return "int64", ""
+	case strings.HasPrefix(base, "tinyint(1)"), strings.HasPrefix(base, "boolean"), strings.HasPrefix(base, "bit"):
+		if nullable {
+			return "sql.NullBool", "database/sql"
+		}
+		return "bool", ""
+	case strings.HasPrefix(base, "tinyint"):
+		if nullable {
+			return "sql.NullInt64", "database/sql"
+		}
+		return "int64", ""
+	case strings.HasPrefix(base, "decimal"), strings.HasPrefix(base, "numeric"):
+		if nullable {
+			return "*decimal.Decimal", "github.com/shopspring/decimal"
+		}
+		return "decimal.Decimal", "github.com/shopspring/decimal"
+	case strings.HasPrefix(base, "float"), strings.HasPrefix(base, "double"):
+		if nullable {
+			return "sql.NullFloat64", "database/sql"
+		}
+		return "float64", ""
+	case strings.HasPrefix(base, "datetime"), strings.HasPrefix(base, "timestamp"), strings.HasPrefix(base, "date"):
+		if nullable {
+			return "sql.NullTime", "database/sql"
+		}
+		return "time.Time", "time"
+	case strings.HasPrefix(base, "blob"), strings.HasPrefix(base, "binary"), strings.HasPrefix(base, "varbinary"):
+		return "[]byte", ""
+	default:
+		// varchar/char/text/nvarchar/ntext 等字符串类型的兜底
+		if nullable {
+			return "sql.NullString", "database/sql"
+		}
+		return "string", ""
+	}
+}
+
+// toPascalCase 把 snake_case（或 kebab-case）转换成 PascalCase，用作 struct/字段名
+func toPascalCase(s string) string {
+	parts := strings.FieldsFunc(s, func(r rune) bool { return r == '_' || r == '-' })
+	for i, p := range parts {
+		if p == "" {
+			continue
+		}
+		parts[i] = strings.ToUpper(p[:1]) + p[1:]
+	}
+	return strings.Join(parts, "")
+}
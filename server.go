@@ -0,0 +1,202 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// serveAddr 是 serve 子命令监听的地址，可通过 LIQUIX_ADDR 覆盖
+const serveAddr = ":8080"
+
+// connParams 是一组 JDBC 连接参数
+type connParams struct {
+	Url      string `json:"url"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// diffRequest 是 POST /diff 的请求体
+type diffRequest struct {
+	Reference connParams `json:"reference"`
+	Target    connParams `json:"target"`
+}
+
+// diffResponse 是 POST /diff 的响应体
+type diffResponse struct {
+	Ddl string `json:"ddl"`
+}
+
+// sqlRequest 是 POST /sql 的请求体
+// Db 是目标库名，用于去除生成的 sql 里的表名前缀
+// Changelog 是要执行的 changelog（通常是 /diff 返回的 ddl），/sql 自己不持有任何跨请求的状态
+type sqlRequest struct {
+	Target    connParams `json:"target"`
+	Db        string     `json:"db"`
+	Changelog string     `json:"changelog"`
+}
+
+// runServe 以 HTTP 服务的形式启动 liquix，按需提供 diff 和 sql 生成能力
+func runServe() {
+	err := os.Setenv("JAVA_TOOL_OPTIONS", "-Dfile.encoding=UTF-8")
+	if err != nil {
+		slog.Error("Error setting JAVA_TOOL_OPTIONS environment variable:", "msg", err)
+		return
+	}
+
+	addr := serveAddr
+	if v := os.Getenv("LIQUIX_ADDR"); v != "" {
+		addr = v
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", handleHealthz)
+	mux.HandleFunc("/diff", handleDiff)
+	mux.HandleFunc("/sql", handleSql)
+
+	slog.Info("Starting liquix server", "addr", addr)
+	if err := http.ListenAndServe(addr, mux); err != nil {
+		slog.Error("Error starting server:", "msg", err)
+	}
+}
+
+func handleHealthz(w http.ResponseWriter, r *http.Request) {
+	writeJson(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// handleDiff 对请求携带的 source/target 连接执行 diffChangeLog，返回生成的 ddl.xml
+func handleDiff(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req diffRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	workDir, err := os.MkdirTemp("", "liquix-diff-")
+	if err != nil {
+		http.Error(w, fmt.Sprintf("error creating temp dir: %v", err), http.StatusInternalServerError)
+		return
+	}
+	defer os.RemoveAll(workDir)
+
+	propertiesFile := filepath.Join(workDir, "diff.properties")
+	err = os.WriteFile(propertiesFile, []byte(fmt.Sprintf(
+		"url=%s\nusername=%s\npassword=%s\nreferenceUrl=%s\nreferenceUsername=%s\nreferencePassword=%s\n",
+		req.Target.Url, req.Target.Username, req.Target.Password,
+		req.Reference.Url, req.Reference.Username, req.Reference.Password,
+	)), 0644)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("error writing properties: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	changeLogFile := filepath.Join(workDir, "ddl.xml")
+	liquibaseDir := liquibaseDir()
+
+	ch := make(chan string)
+	go generateChangeLog(liquibaseDir, changeLogFile, propertiesFile, ch)
+	if s := <-ch; s != success {
+		http.Error(w, "diffChangeLog failed, see server logs", http.StatusInternalServerError)
+		return
+	}
+
+	ddl, err := os.ReadFile(changeLogFile)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("error reading ddl.xml: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	writeJson(w, http.StatusOK, diffResponse{Ddl: string(ddl)})
+}
+
+// handleSql 对请求体里的 changelog 执行 updateSql，并按 ?db= 指定的目标方言转换后返回
+// changelog 由调用方传入（通常就是 /diff 返回的 ddl），/sql 不依赖任何跨请求持久化的路径
+func handleSql(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	dbType := r.URL.Query().Get("db")
+	if _, ok := targetDialects[dbType]; !ok {
+		http.Error(w, fmt.Sprintf("unsupported db type: %s", dbType), http.StatusBadRequest)
+		return
+	}
+
+	var req sqlRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+	if req.Changelog == "" {
+		http.Error(w, "changelog is required", http.StatusBadRequest)
+		return
+	}
+
+	workDir, err := os.MkdirTemp("", "liquix-sql-")
+	if err != nil {
+		http.Error(w, fmt.Sprintf("error creating temp dir: %v", err), http.StatusInternalServerError)
+		return
+	}
+	defer os.RemoveAll(workDir)
+
+	propertiesFile := filepath.Join(workDir, dbType+".properties")
+	err = os.WriteFile(propertiesFile, []byte(fmt.Sprintf(
+		"url=%s\nusername=%s\npassword=%s\ndb: %s\n",
+		req.Target.Url, req.Target.Username, req.Target.Password, req.Db,
+	)), 0644)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("error writing properties: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	changeLogFile := filepath.Join(workDir, "ddl.xml")
+	if err := os.WriteFile(changeLogFile, []byte(req.Changelog), 0644); err != nil {
+		http.Error(w, fmt.Sprintf("error writing changelog: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	lines, err := runUpdateSql(liquibaseDir(), changeLogFile, propertiesFile, dbType)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("error executing liquibase updateSql: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	writeSql(w, http.StatusOK, lines)
+}
+
+// liquibaseDir 返回 liquibase 可执行文件所在目录
+func liquibaseDir() string {
+	currentDir, err := os.Getwd()
+	if err != nil {
+		slog.Error("Error getting current directory:", "msg", err)
+		return "liquibase"
+	}
+	return currentDir + "/liquibase"
+}
+
+func writeJson(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		slog.Error("Error encoding json response:", "msg", err)
+	}
+}
+
+// writeSql 以 application/sql 返回生成的 sql 原文，每条语句一行
+func writeSql(w http.ResponseWriter, status int, lines []string) {
+	w.Header().Set("Content-Type", "application/sql")
+	w.WriteHeader(status)
+	if _, err := w.Write([]byte(strings.Join(lines, "\n"))); err != nil {
+		slog.Error("Error writing sql response:", "msg", err)
+	}
+}